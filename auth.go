@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type contextKey string
+
+const userContextKey contextKey = "authenticatedUser"
+
+const tokenTTL = 24 * time.Hour
+
+// AuthenticatedUser описывает личность вызывающего, извлечённую из JWT.
+type AuthenticatedUser struct {
+	Email  string
+	Scopes []string
+}
+
+type authClaims struct {
+	Email  string   `json:"email"`
+	Scopes []string `json:"scopes"`
+	jwt.RegisteredClaims
+}
+
+// GenerateToken подписывает JWT с email и scopes пользователя секретом
+// secret, со сроком действия tokenTTL.
+func GenerateToken(email string, scopes []string, secret []byte) (string, error) {
+	claims := authClaims{
+		Email:  email,
+		Scopes: scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(secret)
+}
+
+// ParseToken проверяет подпись токена секретом secret, срок действия и
+// возвращает личность пользователя.
+func ParseToken(tokenString string, secret []byte) (AuthenticatedUser, error) {
+	claims := &authClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("неожиданный метод подписи токена")
+		}
+		return secret, nil
+	})
+	if err != nil {
+		return AuthenticatedUser{}, err
+	}
+	if !token.Valid {
+		return AuthenticatedUser{}, errors.New("невалидный токен")
+	}
+	return AuthenticatedUser{Email: claims.Email, Scopes: claims.Scopes}, nil
+}
+
+// AuthMiddleware возвращает middleware, которая проверяет заголовок
+// Authorization: Bearer <token> текущим JWT-секретом из liveConfig (читается
+// заново на каждый запрос, поэтому hot-reload секрета через etcd подхватывается
+// без перезапуска) и добавляет аутентифицированного пользователя в контекст запроса.
+func AuthMiddleware(liveConfig *LiveConfig) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			const prefix = "Bearer "
+			if !strings.HasPrefix(header, prefix) {
+				http.Error(w, "отсутствует токен авторизации", http.StatusUnauthorized)
+				return
+			}
+
+			secret := []byte(liveConfig.Get().JWTSecret)
+			authUser, err := ParseToken(strings.TrimPrefix(header, prefix), secret)
+			if err != nil {
+				http.Error(w, "невалидный токен авторизации", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userContextKey, authUser)
+			next(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// UserFromContext достаёт аутентифицированного пользователя из контекста,
+// куда его кладёт AuthMiddleware.
+func UserFromContext(ctx context.Context) (AuthenticatedUser, bool) {
+	authUser, ok := ctx.Value(userContextKey).(AuthenticatedUser)
+	return authUser, ok
+}
+
+// RequireScope возвращает middleware, который пропускает запрос дальше только
+// если у аутентифицированного пользователя есть указанный scope, иначе
+// отвечает 403. Должен оборачивать хендлер, уже прошедший AuthMiddleware.
+func RequireScope(scope string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			authUser, ok := UserFromContext(r.Context())
+			if !ok || !hasScope(authUser.Scopes, scope) {
+				http.Error(w, "недостаточно прав", http.StatusForbidden)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
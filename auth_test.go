@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestGenerateAndParseTokenRoundTrip(t *testing.T) {
+	secret := []byte("test-secret")
+
+	token, err := GenerateToken("user@example.com", []string{"admin"}, secret)
+	if err != nil {
+		t.Fatalf("GenerateToken вернул ошибку: %v", err)
+	}
+
+	authUser, err := ParseToken(token, secret)
+	if err != nil {
+		t.Fatalf("ParseToken вернул ошибку: %v", err)
+	}
+	if authUser.Email != "user@example.com" {
+		t.Errorf("Email = %q, хотим user@example.com", authUser.Email)
+	}
+	if len(authUser.Scopes) != 1 || authUser.Scopes[0] != "admin" {
+		t.Errorf("Scopes = %v, хотим [admin]", authUser.Scopes)
+	}
+}
+
+func TestParseTokenRejectsWrongSecret(t *testing.T) {
+	token, err := GenerateToken("user@example.com", nil, []byte("real-secret"))
+	if err != nil {
+		t.Fatalf("GenerateToken вернул ошибку: %v", err)
+	}
+
+	if _, err := ParseToken(token, []byte("wrong-secret")); err == nil {
+		t.Error("ParseToken с неверным секретом должен вернуть ошибку")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache — общий интерфейс кэша пользователей. Позволяет CachedUserRep
+// прозрачно переключаться между локальным LRU-кэшем и общим кэшем вроде
+// Redis, когда сервис работает в нескольких репликах. IsListLoaded/
+// MarkListLoaded/InvalidateListLoaded хранят признак "список пользователей
+// полностью загружен в кэш" внутри самого Cache, а не в CachedUserRep: для
+// UserCache это значение само собой локально для процесса, а для RedisCache
+// оно видно всем репликам, разделяющим один Redis.
+type Cache interface {
+	Get(email string) (*User, bool)
+	Set(email string, user *User)
+	Delete(email string)
+	List() []User
+	IsListLoaded() bool
+	MarkListLoaded()
+	InvalidateListLoaded()
+}
+
+const (
+	defaultCacheCapacity = 1000
+	defaultCacheTTL      = 5 * time.Minute
+)
+
+// NewConfiguredCache выбирает реализацию Cache по cfg.CacheBackend
+// ("memory" по умолчанию, либо "redis").
+func NewConfiguredCache(cfg Config) Cache {
+	if cfg.CacheBackend == "redis" {
+		return NewRedisCache(cfg.RedisAddr, cfg.CacheTTL)
+	}
+	return NewUserCache(cfg.CacheCapacity, cfg.CacheTTL)
+}
+
+type cacheEntry struct {
+	email     string
+	user      *User
+	expiresAt time.Time
+}
+
+// UserCache — ограниченный по размеру LRU-кэш с TTL на запись: Get
+// продвигает запись в начало списка очерёдности, а Set вытесняет хвост при
+// превышении capacity.
+type UserCache struct {
+	mutex      sync.Mutex
+	capacity   int
+	ttl        time.Duration
+	entries    map[string]*list.Element
+	order      *list.List
+	listLoaded bool
+}
+
+// NewUserCache создаёт LRU-кэш вместимостью capacity и временем жизни записи ttl.
+func NewUserCache(capacity int, ttl time.Duration) *UserCache {
+	return &UserCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *UserCache) Get(email string) (*User, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, found := c.entries[email]
+	if !found {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.user, true
+}
+
+func (c *UserCache) Set(email string, user *User) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, found := c.entries[email]; found {
+		entry := elem.Value.(*cacheEntry)
+		entry.user = user
+		entry.expiresAt = time.Now().Add(c.ttl)
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	entry := &cacheEntry{email: email, user: user, expiresAt: time.Now().Add(c.ttl)}
+	elem := c.order.PushFront(entry)
+	c.entries[email] = elem
+
+	if c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *UserCache) Delete(email string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, found := c.entries[email]; found {
+		c.removeElement(elem)
+	}
+}
+
+func (c *UserCache) List() []User {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	users := make([]User, 0, c.order.Len())
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		entry := elem.Value.(*cacheEntry)
+		if time.Now().After(entry.expiresAt) {
+			c.removeElement(elem)
+			elem = next
+			continue
+		}
+		users = append(users, *entry.user)
+		elem = next
+	}
+	return users
+}
+
+// removeElement удаляет элемент из списка очерёдности и карты. Вызывающий
+// должен удерживать c.mutex.
+func (c *UserCache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	entry := elem.Value.(*cacheEntry)
+	delete(c.entries, entry.email)
+}
+
+func (c *UserCache) IsListLoaded() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.listLoaded
+}
+
+func (c *UserCache) MarkListLoaded() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.listLoaded = true
+}
+
+func (c *UserCache) InvalidateListLoaded() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.listLoaded = false
+}
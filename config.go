@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config — вся настраиваемая конфигурация сервиса. Собирается LoadConfig из
+// нескольких ConfigProvider и передаётся дальше явно, вместо того чтобы
+// каждый пакет читал переменные окружения сам.
+type Config struct {
+	DBPath        string
+	HTTPAddr      string
+	JWTSecret     string
+	BcryptCost    int
+	CacheBackend  string
+	CacheCapacity int
+	CacheTTL      time.Duration
+	RedisAddr     string
+}
+
+const defaultRedisAddr = "localhost:6379"
+
+// defaultConfig возвращает значения по умолчанию для локальной разработки.
+func defaultConfig() Config {
+	return Config{
+		DBPath:        "users.db",
+		HTTPAddr:      ":8080",
+		JWTSecret:     "dev-secret-change-me",
+		BcryptCost:    bcrypt.DefaultCost,
+		CacheBackend:  "memory",
+		CacheCapacity: defaultCacheCapacity,
+		CacheTTL:      defaultCacheTTL,
+		RedisAddr:     defaultRedisAddr,
+	}
+}
+
+// Validate проверяет, что обязательные поля итоговой конфигурации заполнены.
+func (c Config) Validate() error {
+	if c.DBPath == "" {
+		return errors.New("не задан путь к базе данных (db_path)")
+	}
+	if c.HTTPAddr == "" {
+		return errors.New("не задан адрес HTTP-сервера (http_addr)")
+	}
+	if c.JWTSecret == "" {
+		return errors.New("не задан секрет JWT (jwt_secret)")
+	}
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		return errors.New("некорректная стоимость bcrypt (bcrypt_cost)")
+	}
+	return nil
+}
+
+// ConfigProvider — источник конфигурации. LoadConfig накладывает несколько
+// провайдеров друг на друга по приоритету.
+type ConfigProvider interface {
+	Load() (Config, error)
+}
+
+// mergeConfig накладывает непустые поля override поверх base и возвращает результат.
+func mergeConfig(base, override Config) Config {
+	if override.DBPath != "" {
+		base.DBPath = override.DBPath
+	}
+	if override.HTTPAddr != "" {
+		base.HTTPAddr = override.HTTPAddr
+	}
+	if override.JWTSecret != "" {
+		base.JWTSecret = override.JWTSecret
+	}
+	if override.BcryptCost != 0 {
+		base.BcryptCost = override.BcryptCost
+	}
+	if override.CacheBackend != "" {
+		base.CacheBackend = override.CacheBackend
+	}
+	if override.CacheCapacity != 0 {
+		base.CacheCapacity = override.CacheCapacity
+	}
+	if override.CacheTTL != 0 {
+		base.CacheTTL = override.CacheTTL
+	}
+	if override.RedisAddr != "" {
+		base.RedisAddr = override.RedisAddr
+	}
+	return base
+}
+
+// LoadConfig собирает итоговую конфигурацию из файла по filePath, переменных
+// окружения и, если задан, etcd — в порядке возрастания приоритета: defaults
+// < file < env < etcd. Пустой filePath или отсутствующий файл пропускаются
+// без ошибки, чтобы сервис мог запускаться без -config.
+func LoadConfig(filePath string, etcdProvider ConfigProvider) (Config, error) {
+	cfg := defaultConfig()
+
+	fileCfg, err := NewFileProvider(filePath).Load()
+	if err != nil {
+		return Config{}, err
+	}
+	cfg = mergeConfig(cfg, fileCfg)
+
+	envCfg, err := NewEnvProvider().Load()
+	if err != nil {
+		return Config{}, err
+	}
+	cfg = mergeConfig(cfg, envCfg)
+
+	if etcdProvider != nil {
+		etcdCfg, err := etcdProvider.Load()
+		if err != nil {
+			return Config{}, err
+		}
+		cfg = mergeConfig(cfg, etcdCfg)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// fileConfigFormat — структура файла конфигурации (JSON).
+type fileConfigFormat struct {
+	DBPath          string `json:"db_path"`
+	HTTPAddr        string `json:"http_addr"`
+	JWTSecret       string `json:"jwt_secret"`
+	BcryptCost      int    `json:"bcrypt_cost"`
+	CacheBackend    string `json:"cache_backend"`
+	CacheCapacity   int    `json:"cache_capacity"`
+	CacheTTLSeconds int    `json:"cache_ttl_seconds"`
+	RedisAddr       string `json:"redis_addr"`
+}
+
+// FileProvider читает конфигурацию из JSON-файла, путь к которому задаётся
+// флагом -config.
+type FileProvider struct {
+	Path string
+}
+
+// NewFileProvider создаёт FileProvider для файла по path.
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{Path: path}
+}
+
+// Load возвращает пустой Config{}, если путь не задан или файл не существует:
+// файл конфигурации необязателен.
+func (p *FileProvider) Load() (Config, error) {
+	if p.Path == "" {
+		return Config{}, nil
+	}
+
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Config{}, nil
+		}
+		return Config{}, err
+	}
+
+	var parsed fileConfigFormat
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Config{
+		DBPath:        parsed.DBPath,
+		HTTPAddr:      parsed.HTTPAddr,
+		JWTSecret:     parsed.JWTSecret,
+		BcryptCost:    parsed.BcryptCost,
+		CacheBackend:  parsed.CacheBackend,
+		CacheCapacity: parsed.CacheCapacity,
+		RedisAddr:     parsed.RedisAddr,
+	}
+	if parsed.CacheTTLSeconds > 0 {
+		cfg.CacheTTL = time.Duration(parsed.CacheTTLSeconds) * time.Second
+	}
+	return cfg, nil
+}
+
+// EnvProvider читает конфигурацию из переменных окружения APP_*.
+type EnvProvider struct{}
+
+// NewEnvProvider создаёт EnvProvider.
+func NewEnvProvider() *EnvProvider {
+	return &EnvProvider{}
+}
+
+func (p *EnvProvider) Load() (Config, error) {
+	cfg := Config{
+		DBPath:       os.Getenv("APP_DB_PATH"),
+		HTTPAddr:     os.Getenv("APP_HTTP_ADDR"),
+		JWTSecret:    os.Getenv("APP_JWT_SECRET"),
+		CacheBackend: os.Getenv("APP_CACHE_BACKEND"),
+		RedisAddr:    os.Getenv("APP_REDIS_ADDR"),
+	}
+
+	if raw := os.Getenv("APP_BCRYPT_COST"); raw != "" {
+		if cost, err := strconv.Atoi(raw); err == nil {
+			cfg.BcryptCost = cost
+		}
+	}
+	if raw := os.Getenv("APP_CACHE_CAPACITY"); raw != "" {
+		if capacity, err := strconv.Atoi(raw); err == nil {
+			cfg.CacheCapacity = capacity
+		}
+	}
+	if raw := os.Getenv("APP_CACHE_TTL_SECONDS"); raw != "" {
+		if seconds, err := strconv.Atoi(raw); err == nil {
+			cfg.CacheTTL = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return cfg, nil
+}
+
+// EtcdProvider читает конфигурацию из etcd по префиксу ключей и рассылает
+// подписчикам свежую конфигурацию при изменении любого ключа под префиксом,
+// чтобы долгоживущие компоненты могли применять настройки без рестарта.
+type EtcdProvider struct {
+	client *clientv3.Client
+	prefix string
+
+	mu        sync.RWMutex
+	listeners []chan Config
+}
+
+// NewEtcdProvider создаёт EtcdProvider, читающий ключи под prefix через client.
+func NewEtcdProvider(client *clientv3.Client, prefix string) *EtcdProvider {
+	return &EtcdProvider{client: client, prefix: prefix}
+}
+
+// Load делает единоразовый Get всех ключей под префиксом.
+func (p *EtcdProvider) Load() (Config, error) {
+	resp, err := p.client.Get(context.Background(), p.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return Config{}, err
+	}
+	return etcdConfigFromKVs(p.prefix, resp.Kvs), nil
+}
+
+// Subscribe возвращает канал, в который Watch будет публиковать новую
+// конфигурацию при каждом изменении под префиксом. Вызывающий не должен
+// закрывать канал.
+func (p *EtcdProvider) Subscribe() <-chan Config {
+	ch := make(chan Config, 1)
+	p.mu.Lock()
+	p.listeners = append(p.listeners, ch)
+	p.mu.Unlock()
+	return ch
+}
+
+// Watch запускает фоновое наблюдение за префиксом в etcd до отмены ctx: при
+// изменении любого ключа перечитывает конфигурацию целиком и рассылает её
+// всем подписчикам Subscribe.
+func (p *EtcdProvider) Watch(ctx context.Context) {
+	watchChan := p.client.Watch(ctx, p.prefix, clientv3.WithPrefix())
+	go func() {
+		for range watchChan {
+			cfg, err := p.Load()
+			if err != nil {
+				continue
+			}
+
+			p.mu.RLock()
+			listeners := make([]chan Config, len(p.listeners))
+			copy(listeners, p.listeners)
+			p.mu.RUnlock()
+
+			for _, listener := range listeners {
+				select {
+				case listener <- cfg:
+				default:
+					// Подписчик не вычитывает обновления быстрее, чем приходят новые:
+					// пропускаем это значение, а не блокируем единственную горутину
+					// watch навсегда — следующее изменение в etcd пришлёт более
+					// свежий Config так же, целиком.
+				}
+			}
+		}
+	}()
+}
+
+// LiveConfig — потокобезопасный держатель текущей конфигурации. Долгоживущие
+// компоненты (AuthMiddleware, UserCommandHandlers) читают из него при каждом
+// запросе, а Follow применяет поверх него обновления из EtcdProvider.Subscribe,
+// так что хот-релоад реально доходит до уже сконструированных компонентов, а
+// не только до значения, зафиксированного при старте в LoadConfig.
+type LiveConfig struct {
+	current atomic.Pointer[Config]
+}
+
+// NewLiveConfig создаёт LiveConfig с начальным значением initial.
+func NewLiveConfig(initial Config) *LiveConfig {
+	lc := &LiveConfig{}
+	lc.current.Store(&initial)
+	return lc
+}
+
+// Get возвращает текущую конфигурацию.
+func (lc *LiveConfig) Get() Config {
+	return *lc.current.Load()
+}
+
+// Follow читает обновления из updates (как правило — EtcdProvider.Subscribe)
+// и накладывает их поверх текущей конфигурации через mergeConfig, пока не
+// закроют updates или не отменят ctx.
+func (lc *LiveConfig) Follow(ctx context.Context, updates <-chan Config) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				merged := mergeConfig(lc.Get(), update)
+				lc.current.Store(&merged)
+			}
+		}
+	}()
+}
+
+// etcdConfigFromKVs собирает Config из key/value пар под префиксом prefix.
+// Ключи ожидаются в виде "<prefix>db_path", "<prefix>bcrypt_cost" и т.д.
+func etcdConfigFromKVs(prefix string, kvs []*mvccpb.KeyValue) Config {
+	var cfg Config
+	for _, kv := range kvs {
+		key := strings.TrimPrefix(string(kv.Key), prefix)
+		value := string(kv.Value)
+		switch key {
+		case "db_path":
+			cfg.DBPath = value
+		case "http_addr":
+			cfg.HTTPAddr = value
+		case "jwt_secret":
+			cfg.JWTSecret = value
+		case "cache_backend":
+			cfg.CacheBackend = value
+		case "redis_addr":
+			cfg.RedisAddr = value
+		case "bcrypt_cost":
+			if cost, err := strconv.Atoi(value); err == nil {
+				cfg.BcryptCost = cost
+			}
+		case "cache_capacity":
+			if capacity, err := strconv.Atoi(value); err == nil {
+				cfg.CacheCapacity = capacity
+			}
+		case "cache_ttl_seconds":
+			if seconds, err := strconv.Atoi(value); err == nil {
+				cfg.CacheTTL = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return cfg
+}
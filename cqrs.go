@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// CommandHandler обрабатывает команду и возвращает её результат.
+type CommandHandler func(ctx context.Context, cmd any) (any, error)
+
+// QueryHandler обрабатывает запрос и возвращает его результат.
+type QueryHandler func(ctx context.Context, query any) (any, error)
+
+// CommandHook вызывается после успешного выполнения команды с самой командой
+// и её результатом — например, чтобы инвалидировать кэш.
+type CommandHook func(ctx context.Context, cmd any, result any)
+
+type commandRoute struct {
+	matcher reflect.Type
+	handler CommandHandler
+}
+
+type queryRoute struct {
+	matcher reflect.Type
+	handler QueryHandler
+}
+
+// Dispatcher маршрутизирует команды и запросы зарегистрированным обработчикам
+// по типу значения. Контроллеры зависят только от Dispatcher, а не от
+// разрастающегося интерфейса сервиса.
+type Dispatcher struct {
+	commands []commandRoute
+	queries  []queryRoute
+	hooks    []CommandHook
+}
+
+// NewDispatcher создаёт пустой диспетчер.
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// RegisterCommand связывает тип значения matcher с обработчиком команды.
+func (d *Dispatcher) RegisterCommand(matcher any, handler CommandHandler) {
+	d.commands = append(d.commands, commandRoute{matcher: reflect.TypeOf(matcher), handler: handler})
+}
+
+// RegisterQuery связывает тип значения matcher с обработчиком запроса.
+func (d *Dispatcher) RegisterQuery(matcher any, handler QueryHandler) {
+	d.queries = append(d.queries, queryRoute{matcher: reflect.TypeOf(matcher), handler: handler})
+}
+
+// OnCommand регистрирует хук, вызываемый после успешного выполнения любой команды.
+func (d *Dispatcher) OnCommand(hook CommandHook) {
+	d.hooks = append(d.hooks, hook)
+}
+
+// Dispatch выполняет req через обработчик, зарегистрированный для его типа,
+// сначала проверяя команды, затем запросы.
+func (d *Dispatcher) Dispatch(ctx context.Context, req any) (any, error) {
+	reqType := reflect.TypeOf(req)
+
+	for _, route := range d.commands {
+		if route.matcher != reqType {
+			continue
+		}
+		result, err := route.handler(ctx, req)
+		if err == nil {
+			for _, hook := range d.hooks {
+				hook(ctx, req, result)
+			}
+		}
+		return result, err
+	}
+
+	for _, route := range d.queries {
+		if route.matcher == reqType {
+			return route.handler(ctx, req)
+		}
+	}
+
+	return nil, fmt.Errorf("нет обработчика для %T", req)
+}
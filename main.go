@@ -5,11 +5,17 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/bcrypt"
 	"log"
 	"net/http"
-	"sync"
+	"os"
+	"strconv"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 // Написать мини сервис с разделением слоев в одном main.go файле. Можно писать в Goland
@@ -24,81 +30,79 @@ import (
 // Вывести список всех пользователей
 
 type User struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Name     string `json:"name"`
-	Age      int    `json:"age"`
-}
-
-//Слой кеша
-
-type UserCache struct {
-	users map[string]*User
-	mutex sync.RWMutex
-}
-
-func NewUserCache() *UserCache {
-	return &UserCache{
-		users: make(map[string]*User),
-	}
+	Email    string   `json:"email"`
+	Password string   `json:"password"`
+	Name     string   `json:"name"`
+	Age      int      `json:"age"`
+	Scopes   []string `json:"scopes"`
 }
 
-func (c *UserCache) Get(email string) (*User, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	user, found := c.users[email]
-	return user, found
+// userResponse — то, что сервис отдаёт наружу вместо User: без хэша пароля.
+// Все хендлеры, кодирующие пользователя в HTTP-ответ, должны использовать её.
+type userResponse struct {
+	Email  string   `json:"email"`
+	Name   string   `json:"name"`
+	Age    int      `json:"age"`
+	Scopes []string `json:"scopes"`
 }
 
-func (c *UserCache) Set(email string, user *User) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.users[email] = user
+func toUserResponse(user User) userResponse {
+	return userResponse{Email: user.Email, Name: user.Name, Age: user.Age, Scopes: user.Scopes}
 }
 
-func (c *UserCache) List() []User {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	users := []User{}
-	for _, user := range c.users {
-		users = append(users, *user)
+func toUserResponses(users []User) []userResponse {
+	responses := make([]userResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, toUserResponse(user))
 	}
-	return users
+	return responses
 }
 
+//Слой кеша — реализация UserCache (LRU+TTL) и RedisCache лежит в cache.go
+
+// CachedUserRep — Proxy на стороне запросов: команды пишут напрямую через
+// UserRepository, а актуальность кэша поддерживает хук Dispatcher.OnCommand.
 type CachedUserRep struct {
 	rep   UserRepository
-	cache *UserCache
+	cache Cache
 }
 
-func NewCachedUserRep(rep UserRepository) *CachedUserRep {
+func NewCachedUserRep(rep UserRepository, cache Cache) *CachedUserRep {
 	return &CachedUserRep{
 		rep:   rep,
-		cache: NewUserCache(),
-	}
-}
-
-func (c *CachedUserRep) Create(ctx context.Context, user User) error {
-	err := c.rep.Create(ctx, user)
-	if err == nil {
-		c.cache.Set(user.Email, &user)
+		cache: cache,
 	}
-	return err
 }
 
+// List отдаёт закэшированный список, только если cache был полностью
+// загружен предыдущим успешным обращением к rep.List — непустой кэш сам по
+// себе не значит, что в нём есть все пользователи. Флаг "загружен полностью"
+// живёт в самом Cache (а не в CachedUserRep), чтобы при общем кэше вроде
+// Redis его видели все реплики сервиса, а не только та, что его выставила.
 func (c *CachedUserRep) List(ctx context.Context) ([]User, error) {
-	cachedUsers := c.cache.List()
-	if len(cachedUsers) > 0 {
-		return cachedUsers, nil
+	if c.cache.IsListLoaded() {
+		return c.cache.List(), nil
 	}
 
 	users, err := c.rep.List(ctx)
-	if err == nil {
-		for _, user := range users {
-			c.cache.Set(user.Email, &user)
-		}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		c.cache.Set(user.Email, &user)
+	}
+
+	// MarkListLoaded только если кэш реально удержал всех загруженных
+	// пользователей: у кэшей с ограниченной ёмкостью (UserCache) Set может
+	// вытеснить часть только что записанных записей, и в этом случае список
+	// в кэше неполон — отмечать его "полностью загруженным" нельзя, иначе
+	// последующие List() будут навсегда возвращать усечённый список.
+	if len(c.cache.List()) == len(users) {
+		c.cache.MarkListLoaded()
 	}
-	return users, err
+
+	return users, nil
 }
 
 func (c *CachedUserRep) GetByEmail(email string) (*User, error) {
@@ -117,25 +121,58 @@ func (c *CachedUserRep) GetByEmail(email string) (*User, error) {
 	return user, err
 }
 
+// Put кладёт только что созданного пользователя в кэш и сбрасывает флаг
+// полной загрузки списка, так как список пользователей в БД теперь больше,
+// чем то, что видел последний rep.List.
+func (c *CachedUserRep) Put(user User) {
+	c.cache.Set(user.Email, &user)
+	c.cache.InvalidateListLoaded()
+}
+
+// UpdateScopesInCache обновляет scopes уже закэшированного пользователя, если он там есть.
+func (c *CachedUserRep) UpdateScopesInCache(email string, scopes []string) {
+	user, found := c.cache.Get(email)
+	if !found {
+		return
+	}
+	updated := *user
+	updated.Scopes = scopes
+	c.cache.Set(email, &updated)
+}
+
 //Слой репозиторий __________________________________________________
 
 type UserRepository interface {
 	Create(ctx context.Context, user User) error
 	List(ctx context.Context) ([]User, error)
 	GetByEmail(email string) (*User, error)
+	UpdateScopes(ctx context.Context, email string, scopes []string) error
 }
 
 type UserRep struct {
 	db *sql.DB
 }
 
+// scopesToColumn сериализует scopes в строку для хранения в колонке "scopes".
+func scopesToColumn(scopes []string) string {
+	return strings.Join(scopes, ",")
+}
+
+// scopesFromColumn восстанавливает scopes из колонки "scopes".
+func scopesFromColumn(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
+
 func (u *UserRep) Create(ctx context.Context, user User) error {
-	_, err := u.db.ExecContext(ctx, `INSERT INTO users (email, password, name, age) VALUES ($1, $2, $3, $4)`, user.Email, user.Password, user.Name, user.Age)
+	_, err := u.db.ExecContext(ctx, `INSERT INTO users (email, password_hash, name, age, scopes) VALUES ($1, $2, $3, $4, $5)`, user.Email, user.Password, user.Name, user.Age, scopesToColumn(user.Scopes))
 	return err
 }
 
 func (u *UserRep) List(ctx context.Context) ([]User, error) {
-	rows, err := u.db.QueryContext(ctx, `SELECT email, password, name, age FROM users`)
+	rows, err := u.db.QueryContext(ctx, `SELECT email, password_hash, name, age, scopes FROM users`)
 	if err != nil {
 		return nil, err
 	}
@@ -144,9 +181,11 @@ func (u *UserRep) List(ctx context.Context) ([]User, error) {
 	users := []User{}
 	for rows.Next() {
 		var user User
-		if err := rows.Scan(&user.Email, &user.Password, &user.Name, &user.Age); err != nil {
+		var scopes string
+		if err := rows.Scan(&user.Email, &user.Password, &user.Name, &user.Age, &scopes); err != nil {
 			return nil, err
 		}
+		user.Scopes = scopesFromColumn(scopes)
 		users = append(users, user)
 	}
 	return users, nil
@@ -154,43 +193,164 @@ func (u *UserRep) List(ctx context.Context) ([]User, error) {
 
 func (u *UserRep) GetByEmail(email string) (*User, error) {
 	var user User
-	row := u.db.QueryRow(`SELECT email, password, name, age FROM users WHERE email = $1`, email)
-	err := row.Scan(&user.Email, &user.Password, &user.Name, &user.Age)
+	var scopes string
+	row := u.db.QueryRow(`SELECT email, password_hash, name, age, scopes FROM users WHERE email = $1`, email)
+	err := row.Scan(&user.Email, &user.Password, &user.Name, &user.Age, &scopes)
 	if err == sql.ErrNoRows {
 		return nil, nil // Если пользователь не найден, возвращаем nil
 	}
-	return &user, err
+	if err != nil {
+		return nil, err
+	}
+	user.Scopes = scopesFromColumn(scopes)
+	return &user, nil
+}
+
+func (u *UserRep) UpdateScopes(ctx context.Context, email string, scopes []string) error {
+	_, err := u.db.ExecContext(ctx, `UPDATE users SET scopes = $1 WHERE email = $2`, scopesToColumn(scopes), email)
+	return err
 }
 
 //Слой сервис __________________________________________________
 
-type UserService interface {
-	Create(ctx context.Context, user User) error
-	List(ctx context.Context) ([]User, error)
+// CreateUserCommand регистрирует нового пользователя. Если OrganizationID
+// задан (ненулевой), пользователь также добавляется в эту организацию.
+type CreateUserCommand struct {
+	Email          string
+	Password       string
+	Name           string
+	Age            int
+	Scopes         []string
+	OrganizationID int64
+}
+
+// UpdateScopesCommand меняет scopes существующего пользователя.
+type UpdateScopesCommand struct {
+	Email  string
+	Scopes []string
+}
+
+// LoginCommand проверяет учётные данные и выдаёт JWT при успехе.
+type LoginCommand struct {
+	Email    string
+	Password string
+}
+
+// ListUsersQuery возвращает всех пользователей.
+type ListUsersQuery struct{}
+
+// FindUserByEmailQuery возвращает одного пользователя по email.
+type FindUserByEmailQuery struct {
+	Email string
+}
+
+// UserCommandHandlers пишет через "сырой" UserRepository — кэш на стороне
+// запросов актуализируется отдельным хуком после успешной команды. orgRep
+// используется только для автоматического добавления в организацию при
+// регистрации, если CreateUserCommand.OrganizationID задан. bcryptCost и
+// jwtSecret читаются из liveConfig при каждой команде, а не зафиксированы при
+// конструировании, чтобы hot-reload через etcd доходил и до уже запущенных
+// обработчиков.
+type UserCommandHandlers struct {
+	rep        UserRepository
+	orgRep     OrganizationRepository
+	liveConfig *LiveConfig
 }
 
-type UserServ struct {
-	rep UserRepository
+func NewUserCommandHandlers(rep UserRepository, orgRep OrganizationRepository, liveConfig *LiveConfig) *UserCommandHandlers {
+	return &UserCommandHandlers{
+		rep:        rep,
+		orgRep:     orgRep,
+		liveConfig: liveConfig,
+	}
 }
 
-func (u *UserServ) Create(ctx context.Context, user User) error {
-	if user.Age < 18 {
-		return errors.New("возраст пользователя меньше 18 лет")
+func (h *UserCommandHandlers) HandleCreateUserCommand(ctx context.Context, req any) (any, error) {
+	cmd := req.(CreateUserCommand)
+
+	if cmd.Age < 18 {
+		return nil, errors.New("возраст пользователя меньше 18 лет")
 	}
 
-	existingUser, err := u.rep.GetByEmail(user.Email)
+	existingUser, err := h.rep.GetByEmail(cmd.Email)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if existingUser != nil {
-		return errors.New("пользователь с таким email уже зарегистрирован")
+		return nil, errors.New("пользователь с таким email уже зарегистрирован")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(cmd.Password), h.liveConfig.Get().BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := User{Email: cmd.Email, Password: string(hash), Name: cmd.Name, Age: cmd.Age, Scopes: cmd.Scopes}
+	if err := h.rep.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if cmd.OrganizationID != 0 {
+		if err := h.orgRep.AddMember(ctx, cmd.OrganizationID, user.Email, "member"); err != nil {
+			return nil, err
+		}
 	}
 
-	return u.rep.Create(ctx, user)
+	return user, nil
 }
 
-func (u *UserServ) List(ctx context.Context) ([]User, error) {
-	return u.rep.List(ctx)
+func (h *UserCommandHandlers) HandleUpdateScopesCommand(ctx context.Context, req any) (any, error) {
+	cmd := req.(UpdateScopesCommand)
+
+	existingUser, err := h.rep.GetByEmail(cmd.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser == nil {
+		return nil, errors.New("пользователь не найден")
+	}
+
+	if err := h.rep.UpdateScopes(ctx, cmd.Email, cmd.Scopes); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func (h *UserCommandHandlers) HandleLoginCommand(ctx context.Context, req any) (any, error) {
+	cmd := req.(LoginCommand)
+
+	user, err := h.rep.GetByEmail(cmd.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("неверный email или пароль")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(cmd.Password)); err != nil {
+		return nil, errors.New("неверный email или пароль")
+	}
+
+	return GenerateToken(user.Email, user.Scopes, []byte(h.liveConfig.Get().JWTSecret))
+}
+
+// UserQueryHandlers читает через CachedUserRep, поэтому запросы обслуживаются
+// из кэша, когда это возможно.
+type UserQueryHandlers struct {
+	cachedRep *CachedUserRep
+}
+
+func NewUserQueryHandlers(cachedRep *CachedUserRep) *UserQueryHandlers {
+	return &UserQueryHandlers{cachedRep: cachedRep}
+}
+
+func (h *UserQueryHandlers) HandleListUsersQuery(ctx context.Context, req any) (any, error) {
+	return h.cachedRep.List(ctx)
+}
+
+func (h *UserQueryHandlers) HandleFindUserByEmailQuery(ctx context.Context, req any) (any, error) {
+	query := req.(FindUserByEmailQuery)
+	return h.cachedRep.GetByEmail(query.Email)
 }
 
 // cлой контролер ________________________________________
@@ -198,69 +358,358 @@ func (u *UserServ) List(ctx context.Context) ([]User, error) {
 type UserController interface {
 	Create(w http.ResponseWriter, r *http.Request)
 	List(w http.ResponseWriter, r *http.Request)
+	Login(w http.ResponseWriter, r *http.Request)
+	Me(w http.ResponseWriter, r *http.Request)
 }
 
 type UserContr struct {
-	serv UserService
+	dispatcher *Dispatcher
+}
+
+type createUserRequest struct {
+	Email          string   `json:"email"`
+	Password       string   `json:"password"`
+	Name           string   `json:"name"`
+	Age            int      `json:"age"`
+	Scopes         []string `json:"scopes"`
+	OrganizationID int64    `json:"organization_id"`
 }
 
 func (u *UserContr) Create(w http.ResponseWriter, r *http.Request) {
-	var user User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	var req createUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if err := u.serv.Create(r.Context(), user); err != nil {
+	if len(req.Scopes) > 0 {
+		http.Error(w, "нельзя задавать scopes при регистрации", http.StatusForbidden)
+		return
+	}
+
+	cmd := CreateUserCommand{
+		Email:          req.Email,
+		Password:       req.Password,
+		Name:           req.Name,
+		Age:            req.Age,
+		Scopes:         []string{"user"},
+		OrganizationID: req.OrganizationID,
+	}
+	if _, err := u.dispatcher.Dispatch(r.Context(), cmd); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
 }
 
+func (u *UserContr) Me(w http.ResponseWriter, r *http.Request) {
+	authUser, ok := UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "не найден аутентифицированный пользователь", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := u.dispatcher.Dispatch(r.Context(), FindUserByEmailQuery{Email: authUser.Email})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user, _ := result.(*User)
+	if user == nil {
+		http.Error(w, "пользователь не найден", http.StatusNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(toUserResponse(*user)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+type updateScopesRequest struct {
+	Scopes []string `json:"scopes"`
+}
+
+// UpdateScopes обрабатывает PATCH /users/{email}/scopes. Вызывающий должен
+// быть пропущен через AuthMiddleware и RequireScope("admin").
+func (u *UserContr) UpdateScopes(w http.ResponseWriter, r *http.Request, email string) {
+	var req updateScopesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := u.dispatcher.Dispatch(r.Context(), UpdateScopesCommand{Email: email, Scopes: req.Scopes}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseUserScopesPath разбирает путь вида /users/{email}/scopes и возвращает email.
+func parseUserScopesPath(path string) (string, bool) {
+	const prefix = "/users/"
+	const suffix = "/scopes"
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
+		return "", false
+	}
+	email := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	if email == "" {
+		return "", false
+	}
+	return email, true
+}
+
 func (u *UserContr) List(w http.ResponseWriter, r *http.Request) {
-	users, err := u.serv.List(r.Context())
+	result, err := u.dispatcher.Dispatch(r.Context(), ListUsersQuery{})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err := json.NewEncoder(w).Encode(users); err != nil {
+
+	users, _ := result.([]User)
+	if err := json.NewEncoder(w).Encode(toUserResponses(users)); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	Token string `json:"token"`
+}
+
+func (u *UserContr) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := u.dispatcher.Dispatch(r.Context(), LoginCommand{Email: req.Email, Password: req.Password})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	token, _ := result.(string)
+
+	if err := json.NewEncoder(w).Encode(loginResponse{Token: token}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// configPath указывает путь к необязательному JSON-файлу конфигурации.
+var configPath = flag.String("config", "", "путь к файлу конфигурации (JSON)")
+
+// newEtcdProvider создаёт EtcdProvider, если задана переменная окружения
+// APP_ETCD_ENDPOINTS (через запятую), иначе возвращает nil — тогда
+// конфигурация собирается только из файла и окружения.
+func newEtcdProvider() (*EtcdProvider, error) {
+	endpoints := os.Getenv("APP_ETCD_ENDPOINTS")
+	if endpoints == "" {
+		return nil, nil
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints: strings.Split(endpoints, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEtcdProvider(client, os.Getenv("APP_ETCD_PREFIX")), nil
+}
+
 func main() {
-	db, err := InitDB("users.db")
+	flag.Parse()
+
+	etcdProvider, err := newEtcdProvider()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var etcdUpdates <-chan Config
+	if etcdProvider != nil {
+		etcdUpdates = etcdProvider.Subscribe()
+		etcdProvider.Watch(context.Background())
+	}
+
+	var provider ConfigProvider
+	if etcdProvider != nil {
+		provider = etcdProvider
+	}
+	cfg, err := LoadConfig(*configPath, provider)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	liveConfig := NewLiveConfig(cfg)
+	if etcdProvider != nil {
+		liveConfig.Follow(context.Background(), etcdUpdates)
+	}
+
+	db, err := InitDB(cfg.DBPath)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
 	fmt.Println("База данных инициализирована")
 
-	err = CreateTable(db)
-	if err != nil {
+	migrator := NewMigrator(db, registeredMigrations)
+
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		runMigrateCommand(migrator, args[1:])
+		return
+	}
+
+	if err := migrator.Up(context.Background()); err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println("Таблица users создана")
+	fmt.Println("Миграции применены")
 
 	userRep := &UserRep{db: db}
-	cachedUserRep := NewCachedUserRep(userRep)
-	userServ := &UserServ{rep: cachedUserRep}
-	userContr := &UserContr{serv: userServ}
+	cachedUserRep := NewCachedUserRep(userRep, NewConfiguredCache(cfg))
+
+	orgRep := &OrganizationRep{db: db}
+	cachedOrgRep := NewCachedOrganizationRep(orgRep, NewOrganizationCache())
+
+	commandHandlers := NewUserCommandHandlers(userRep, orgRep, liveConfig)
+	queryHandlers := NewUserQueryHandlers(cachedUserRep)
+	orgCommandHandlers := NewOrganizationCommandHandlers(orgRep)
+	orgQueryHandlers := NewOrganizationQueryHandlers(cachedOrgRep)
+
+	dispatcher := NewDispatcher()
+	dispatcher.RegisterCommand(CreateUserCommand{}, commandHandlers.HandleCreateUserCommand)
+	dispatcher.RegisterCommand(UpdateScopesCommand{}, commandHandlers.HandleUpdateScopesCommand)
+	dispatcher.RegisterCommand(LoginCommand{}, commandHandlers.HandleLoginCommand)
+	dispatcher.RegisterQuery(ListUsersQuery{}, queryHandlers.HandleListUsersQuery)
+	dispatcher.RegisterQuery(FindUserByEmailQuery{}, queryHandlers.HandleFindUserByEmailQuery)
+	dispatcher.RegisterCommand(CreateOrganizationCommand{}, orgCommandHandlers.HandleCreateOrganizationCommand)
+	dispatcher.RegisterCommand(AddOrganizationMemberCommand{}, orgCommandHandlers.HandleAddOrganizationMemberCommand)
+	dispatcher.RegisterCommand(RemoveOrganizationMemberCommand{}, orgCommandHandlers.HandleRemoveOrganizationMemberCommand)
+	dispatcher.RegisterQuery(ListOrganizationMembersQuery{}, orgQueryHandlers.HandleListOrganizationMembersQuery)
+	dispatcher.RegisterQuery(ListOrganizationsForUserQuery{}, orgQueryHandlers.HandleListOrganizationsForUserQuery)
+	dispatcher.OnCommand(func(ctx context.Context, cmd any, result any) {
+		switch c := cmd.(type) {
+		case CreateUserCommand:
+			if user, ok := result.(User); ok {
+				cachedUserRep.Put(user)
+			}
+			if c.OrganizationID != 0 {
+				cachedOrgRep.InvalidateMembers(c.OrganizationID)
+				cachedOrgRep.InvalidateOrganizationsForUser(c.Email)
+			}
+		case UpdateScopesCommand:
+			cachedUserRep.UpdateScopesInCache(c.Email, c.Scopes)
+		case CreateOrganizationCommand:
+			cachedOrgRep.InvalidateOrganizationsForUser(c.OwnerEmail)
+		case AddOrganizationMemberCommand:
+			cachedOrgRep.InvalidateMembers(c.OrgID)
+			cachedOrgRep.InvalidateOrganizationsForUser(c.Email)
+		case RemoveOrganizationMemberCommand:
+			cachedOrgRep.InvalidateMembers(c.OrgID)
+			cachedOrgRep.InvalidateOrganizationsForUser(c.Email)
+		}
+	})
+
+	userContr := &UserContr{dispatcher: dispatcher}
+	orgContr := &OrganizationContr{dispatcher: dispatcher}
+	authMiddleware := AuthMiddleware(liveConfig)
 
 	http.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
 			userContr.Create(w, r)
 		case http.MethodGet:
-			userContr.List(w, r)
+			authMiddleware(userContr.List)(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	http.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userContr.Login(w, r)
+	})
+
+	http.HandleFunc("/users/me", authMiddleware(userContr.Me))
+
+	http.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
+		if email, ok := parseUserScopesPath(r.URL.Path); ok {
+			if r.Method != http.MethodPatch {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			authMiddleware(RequireScope("admin")(func(w http.ResponseWriter, r *http.Request) {
+				userContr.UpdateScopes(w, r, email)
+			}))(w, r)
+			return
+		}
+
+		if email, ok := parseUserOrganizationsPath(r.URL.Path); ok {
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				orgContr.ListForUser(w, r, email)
+			})(w, r)
+			return
+		}
+
+		http.NotFound(w, r)
+	})
+
+	http.HandleFunc("/organizations", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		authMiddleware(orgContr.Create)(w, r)
+	})
+
+	http.HandleFunc("/organizations/", func(w http.ResponseWriter, r *http.Request) {
+		if orgID, email, ok := parseOrganizationMemberPath(r.URL.Path); ok {
+			if r.Method != http.MethodDelete {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				orgContr.RemoveMember(w, r, orgID, email)
+			})(w, r)
+			return
+		}
+
+		orgID, ok := parseOrganizationMembersPath(r.URL.Path)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				orgContr.ListMembers(w, r, orgID)
+			})(w, r)
+		case http.MethodPost:
+			authMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				orgContr.AddMember(w, r, orgID)
+			})(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
 
-	fmt.Println("Сервер запущен на порту :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	fmt.Printf("Сервер запущен на порту %s\n", cfg.HTTPAddr)
+	log.Fatal(http.ListenAndServe(cfg.HTTPAddr, nil))
 }
 
 // Создание бд и миграция _________________________________-
@@ -273,15 +722,45 @@ func InitDB(filepath string) (*sql.DB, error) {
 	return db, nil
 }
 
-func CreateTable(db *sql.DB) error {
-	createTableSQL := `CREATE TABLE IF NOT EXISTS users (
-		"id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-		"email" TEXT NOT NULL,
-		"password" TEXT NOT NULL,
-		"name" TEXT,
-		"age" INTEGER
-	);`
+// runMigrateCommand обрабатывает подкоманды "./app migrate up|down [target]|status".
+func runMigrateCommand(migrator *Migrator, args []string) {
+	ctx := context.Background()
+	if len(args) == 0 {
+		log.Fatal("использование: migrate up|down [target]|status")
+	}
 
-	_, err := db.Exec(createTableSQL)
-	return err
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(ctx); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Миграции применены")
+	case "down":
+		target := 0
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil {
+				log.Fatalf("некорректная целевая версия: %v", err)
+			}
+			target = parsed
+		}
+		if err := migrator.Down(ctx, target); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Откат миграций выполнен")
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%d\t%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		log.Fatalf("неизвестная подкоманда migrate: %s", args[0])
+	}
 }
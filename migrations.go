@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// Migration описывает одну версию схемы БД.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+	Down    func(tx *sql.Tx) error
+}
+
+// Migrator применяет и откатывает миграции, отслеживая применённые версии
+// в таблице schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator создаёт Migrator с переданными миграциями, отсортированными по версии.
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+func (m *Migrator) ensureSchemaTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		"version" INTEGER NOT NULL PRIMARY KEY,
+		"applied_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);`)
+	return err
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context) (map[int]bool, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, nil
+}
+
+// Up применяет все ещё не применённые миграции по возрастанию версии внутри
+// одной транзакции: если хотя бы одна миграция падает, откатывается весь пакет.
+func (m *Migrator) Up(ctx context.Context) error {
+	if err := m.ensureSchemaTable(ctx); err != nil {
+		return err
+	}
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, migration := range m.migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		if err := migration.Up(tx); err != nil {
+			return fmt.Errorf("миграция %d (%s): %w", migration.Version, migration.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, migration.Version); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Down откатывает применённые миграции с версией выше target, в порядке убывания,
+// внутри одной транзакции.
+func (m *Migrator) Down(ctx context.Context, target int) error {
+	if err := m.ensureSchemaTable(ctx); err != nil {
+		return err
+	}
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= target || !applied[migration.Version] {
+			continue
+		}
+		if err := migration.Down(tx); err != nil {
+			return fmt.Errorf("откат миграции %d (%s): %w", migration.Version, migration.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// MigrationStatus описывает состояние одной миграции для вывода подкоманды status.
+type MigrationStatus struct {
+	Version int
+	Name    string
+	Applied bool
+}
+
+// Status возвращает состояние каждой зарегистрированной миграции.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.ensureSchemaTable(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		statuses = append(statuses, MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: applied[migration.Version],
+		})
+	}
+	return statuses, nil
+}
+
+// registeredMigrations перечисляет все миграции схемы в порядке их появления.
+var registeredMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_users_table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS users (
+				"id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+				"email" TEXT NOT NULL,
+				"password" TEXT NOT NULL,
+				"name" TEXT,
+				"age" INTEGER
+			);`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DROP TABLE IF EXISTS users;`)
+			return err
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_password_hash_and_scopes",
+		Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`ALTER TABLE users RENAME COLUMN password TO password_hash;`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`ALTER TABLE users ADD COLUMN scopes TEXT NOT NULL DEFAULT '';`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`ALTER TABLE users DROP COLUMN scopes;`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`ALTER TABLE users RENAME COLUMN password_hash TO password;`)
+			return err
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_organizations_tables",
+		Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS organizations (
+				"id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+				"name" TEXT NOT NULL,
+				"created_at" TIMESTAMP NOT NULL
+			);`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`CREATE TABLE IF NOT EXISTS user_organizations (
+				"user_email" TEXT NOT NULL,
+				"org_id" INTEGER NOT NULL,
+				"role" TEXT NOT NULL,
+				PRIMARY KEY ("user_email", "org_id")
+			);`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`DROP TABLE IF EXISTS user_organizations;`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`DROP TABLE IF EXISTS organizations;`)
+			return err
+		},
+	},
+}
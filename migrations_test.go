@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigratorUpAndDown(t *testing.T) {
+	db, err := InitDB(":memory:")
+	if err != nil {
+		t.Fatalf("InitDB вернул ошибку: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator := NewMigrator(db, registeredMigrations)
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up вернул ошибку: %v", err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status вернул ошибку: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.Applied {
+			t.Errorf("миграция %d (%s) не применена после Up", s.Version, s.Name)
+		}
+	}
+
+	if err := migrator.Down(ctx, 0); err != nil {
+		t.Fatalf("Down вернул ошибку: %v", err)
+	}
+
+	statuses, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status вернул ошибку: %v", err)
+	}
+	for _, s := range statuses {
+		if s.Applied {
+			t.Errorf("миграция %d (%s) всё ещё применена после Down(0)", s.Version, s.Name)
+		}
+	}
+}
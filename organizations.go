@@ -0,0 +1,542 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Organization — группа пользователей с общим пространством ресурсов.
+type Organization struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership описывает роль пользователя в организации.
+type Membership struct {
+	Email string `json:"email"`
+	OrgID int64  `json:"org_id"`
+	Role  string `json:"role"`
+}
+
+//Слой репозиторий организаций __________________________________________
+
+type OrganizationRepository interface {
+	Create(ctx context.Context, org Organization) (Organization, error)
+	AddMember(ctx context.Context, orgID int64, email, role string) error
+	RemoveMember(ctx context.Context, orgID int64, email string) error
+	ListForUser(ctx context.Context, email string) ([]Organization, error)
+	ListMembers(ctx context.Context, orgID int64) ([]Membership, error)
+}
+
+type OrganizationRep struct {
+	db *sql.DB
+}
+
+func (o *OrganizationRep) Create(ctx context.Context, org Organization) (Organization, error) {
+	res, err := o.db.ExecContext(ctx, `INSERT INTO organizations (name, created_at) VALUES ($1, $2)`, org.Name, org.CreatedAt)
+	if err != nil {
+		return Organization{}, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Organization{}, err
+	}
+	org.ID = id
+	return org, nil
+}
+
+func (o *OrganizationRep) AddMember(ctx context.Context, orgID int64, email, role string) error {
+	_, err := o.db.ExecContext(ctx, `INSERT INTO user_organizations (user_email, org_id, role) VALUES ($1, $2, $3)`, email, orgID, role)
+	return err
+}
+
+func (o *OrganizationRep) RemoveMember(ctx context.Context, orgID int64, email string) error {
+	_, err := o.db.ExecContext(ctx, `DELETE FROM user_organizations WHERE org_id = $1 AND user_email = $2`, orgID, email)
+	return err
+}
+
+func (o *OrganizationRep) ListForUser(ctx context.Context, email string) ([]Organization, error) {
+	rows, err := o.db.QueryContext(ctx, `
+		SELECT o.id, o.name, o.created_at
+		FROM organizations o
+		JOIN user_organizations uo ON uo.org_id = o.id
+		WHERE uo.user_email = $1`, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []Organization{}
+	for rows.Next() {
+		var org Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
+
+func (o *OrganizationRep) ListMembers(ctx context.Context, orgID int64) ([]Membership, error) {
+	rows, err := o.db.QueryContext(ctx, `SELECT user_email, org_id, role FROM user_organizations WHERE org_id = $1`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []Membership{}
+	for rows.Next() {
+		var m Membership
+		if err := rows.Scan(&m.Email, &m.OrgID, &m.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+//Слой кеша организаций __________________________________________________
+
+// OrganizationCache — простой in-memory кэш, зеркалирующий Proxy-паттерн
+// CachedUserRep для запросов по организациям.
+type OrganizationCache struct {
+	mutex        sync.RWMutex
+	membersByOrg map[int64][]Membership
+	orgsByUser   map[string][]Organization
+}
+
+func NewOrganizationCache() *OrganizationCache {
+	return &OrganizationCache{
+		membersByOrg: make(map[int64][]Membership),
+		orgsByUser:   make(map[string][]Organization),
+	}
+}
+
+func (c *OrganizationCache) getMembers(orgID int64) ([]Membership, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	members, found := c.membersByOrg[orgID]
+	return members, found
+}
+
+func (c *OrganizationCache) setMembers(orgID int64, members []Membership) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.membersByOrg[orgID] = members
+}
+
+func (c *OrganizationCache) invalidateMembers(orgID int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.membersByOrg, orgID)
+}
+
+func (c *OrganizationCache) getOrganizationsForUser(email string) ([]Organization, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	orgs, found := c.orgsByUser[email]
+	return orgs, found
+}
+
+func (c *OrganizationCache) setOrganizationsForUser(email string, orgs []Organization) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.orgsByUser[email] = orgs
+}
+
+func (c *OrganizationCache) invalidateOrganizationsForUser(email string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.orgsByUser, email)
+}
+
+// CachedOrganizationRep — Proxy на стороне запросов, как и CachedUserRep:
+// команды пишут через "сырой" OrganizationRep, а кэш инвалидируется хуком
+// Dispatcher.OnCommand.
+type CachedOrganizationRep struct {
+	rep   OrganizationRepository
+	cache *OrganizationCache
+}
+
+func NewCachedOrganizationRep(rep OrganizationRepository, cache *OrganizationCache) *CachedOrganizationRep {
+	return &CachedOrganizationRep{rep: rep, cache: cache}
+}
+
+func (c *CachedOrganizationRep) ListForUser(ctx context.Context, email string) ([]Organization, error) {
+	if orgs, found := c.cache.getOrganizationsForUser(email); found {
+		return orgs, nil
+	}
+
+	orgs, err := c.rep.ListForUser(ctx, email)
+	if err == nil {
+		c.cache.setOrganizationsForUser(email, orgs)
+	}
+	return orgs, err
+}
+
+func (c *CachedOrganizationRep) ListMembers(ctx context.Context, orgID int64) ([]Membership, error) {
+	if members, found := c.cache.getMembers(orgID); found {
+		return members, nil
+	}
+
+	members, err := c.rep.ListMembers(ctx, orgID)
+	if err == nil {
+		c.cache.setMembers(orgID, members)
+	}
+	return members, err
+}
+
+// InvalidateMembers сбрасывает закэшированный список участников организации.
+func (c *CachedOrganizationRep) InvalidateMembers(orgID int64) {
+	c.cache.invalidateMembers(orgID)
+}
+
+// InvalidateOrganizationsForUser сбрасывает закэшированный список организаций пользователя.
+func (c *CachedOrganizationRep) InvalidateOrganizationsForUser(email string) {
+	c.cache.invalidateOrganizationsForUser(email)
+}
+
+//Слой сервис (CQRS) организаций __________________________________________
+
+// CreateOrganizationCommand создаёт организацию; создатель становится её владельцем.
+type CreateOrganizationCommand struct {
+	Name       string
+	OwnerEmail string
+}
+
+// AddOrganizationMemberCommand добавляет участника в организацию с заданной ролью.
+type AddOrganizationMemberCommand struct {
+	OrgID int64
+	Email string
+	Role  string
+}
+
+// RemoveOrganizationMemberCommand убирает участника из организации.
+type RemoveOrganizationMemberCommand struct {
+	OrgID int64
+	Email string
+}
+
+// ListOrganizationMembersQuery возвращает участников организации.
+type ListOrganizationMembersQuery struct {
+	OrgID int64
+}
+
+// ListOrganizationsForUserQuery возвращает организации, в которых состоит пользователь.
+type ListOrganizationsForUserQuery struct {
+	Email string
+}
+
+// OrganizationCommandHandlers пишет через "сырой" OrganizationRepository —
+// кэш запросов актуализируется отдельным хуком после успешной команды.
+type OrganizationCommandHandlers struct {
+	rep OrganizationRepository
+}
+
+func NewOrganizationCommandHandlers(rep OrganizationRepository) *OrganizationCommandHandlers {
+	return &OrganizationCommandHandlers{rep: rep}
+}
+
+func (h *OrganizationCommandHandlers) HandleCreateOrganizationCommand(ctx context.Context, req any) (any, error) {
+	cmd := req.(CreateOrganizationCommand)
+
+	org, err := h.rep.Create(ctx, Organization{Name: cmd.Name, CreatedAt: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.rep.AddMember(ctx, org.ID, cmd.OwnerEmail, "owner"); err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+func (h *OrganizationCommandHandlers) HandleAddOrganizationMemberCommand(ctx context.Context, req any) (any, error) {
+	cmd := req.(AddOrganizationMemberCommand)
+	if err := h.rep.AddMember(ctx, cmd.OrgID, cmd.Email, cmd.Role); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func (h *OrganizationCommandHandlers) HandleRemoveOrganizationMemberCommand(ctx context.Context, req any) (any, error) {
+	cmd := req.(RemoveOrganizationMemberCommand)
+	if err := h.rep.RemoveMember(ctx, cmd.OrgID, cmd.Email); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// OrganizationQueryHandlers читает через CachedOrganizationRep.
+type OrganizationQueryHandlers struct {
+	cachedRep *CachedOrganizationRep
+}
+
+func NewOrganizationQueryHandlers(cachedRep *CachedOrganizationRep) *OrganizationQueryHandlers {
+	return &OrganizationQueryHandlers{cachedRep: cachedRep}
+}
+
+func (h *OrganizationQueryHandlers) HandleListOrganizationMembersQuery(ctx context.Context, req any) (any, error) {
+	query := req.(ListOrganizationMembersQuery)
+	return h.cachedRep.ListMembers(ctx, query.OrgID)
+}
+
+func (h *OrganizationQueryHandlers) HandleListOrganizationsForUserQuery(ctx context.Context, req any) (any, error) {
+	query := req.(ListOrganizationsForUserQuery)
+	return h.cachedRep.ListForUser(ctx, query.Email)
+}
+
+//Слой контролер организаций ______________________________________________
+
+type OrganizationContr struct {
+	dispatcher *Dispatcher
+}
+
+type createOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+func (o *OrganizationContr) Create(w http.ResponseWriter, r *http.Request) {
+	authUser, ok := UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "не найден аутентифицированный пользователь", http.StatusUnauthorized)
+		return
+	}
+
+	var req createOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := o.dispatcher.Dispatch(r.Context(), CreateOrganizationCommand{Name: req.Name, OwnerEmail: authUser.Email})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// ListMembers обрабатывает GET /organizations/{id}/members — доступно участникам
+// организации или вызывающим со scope "admin".
+func (o *OrganizationContr) ListMembers(w http.ResponseWriter, r *http.Request, orgID int64) {
+	authUser, ok := UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "не найден аутентифицированный пользователь", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := o.dispatcher.Dispatch(r.Context(), ListOrganizationMembersQuery{OrgID: orgID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	members, _ := result.([]Membership)
+	if !hasScope(authUser.Scopes, "admin") && !isOrganizationMember(members, authUser.Email) {
+		http.Error(w, "недостаточно прав", http.StatusForbidden)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(members); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+type addOrganizationMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// AddMember обрабатывает POST /organizations/{id}/members — доступно участникам
+// организации или вызывающим со scope "admin". Роль, которую можно выдать,
+// ограничена собственной ролью вызывающего в организации: только "owner"
+// может выдать роль "owner" или "admin", иначе любой участник может выдать
+// "member" (см. canGrantOrganizationRole).
+func (o *OrganizationContr) AddMember(w http.ResponseWriter, r *http.Request, orgID int64) {
+	authUser, ok := UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "не найден аутентифицированный пользователь", http.StatusUnauthorized)
+		return
+	}
+
+	var req addOrganizationMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := o.dispatcher.Dispatch(r.Context(), ListOrganizationMembersQuery{OrgID: orgID})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	existing, _ := result.([]Membership)
+
+	if !hasScope(authUser.Scopes, "admin") {
+		callerRole, isMember := organizationRole(existing, authUser.Email)
+		if !isMember || !canGrantOrganizationRole(callerRole, req.Role) {
+			http.Error(w, "недостаточно прав", http.StatusForbidden)
+			return
+		}
+	}
+
+	if _, err := o.dispatcher.Dispatch(r.Context(), AddOrganizationMemberCommand{OrgID: orgID, Email: req.Email, Role: req.Role}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// RemoveMember обрабатывает DELETE /organizations/{id}/members/{email} —
+// вызывающий может удалить сам себя; удалить кого-то ещё может участник с
+// ролью "owner"/"admin" или вызывающий со scope "admin". Как и в AddMember,
+// удаление участника с ролью "owner"/"admin" требует, чтобы сам вызывающий
+// был "owner".
+func (o *OrganizationContr) RemoveMember(w http.ResponseWriter, r *http.Request, orgID int64, email string) {
+	authUser, ok := UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "не найден аутентифицированный пользователь", http.StatusUnauthorized)
+		return
+	}
+
+	if authUser.Email != email && !hasScope(authUser.Scopes, "admin") {
+		result, err := o.dispatcher.Dispatch(r.Context(), ListOrganizationMembersQuery{OrgID: orgID})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		existing, _ := result.([]Membership)
+
+		callerRole, isMember := organizationRole(existing, authUser.Email)
+		targetRole, _ := organizationRole(existing, email)
+		if !isMember || !canGrantOrganizationRole(callerRole, targetRole) {
+			http.Error(w, "недостаточно прав", http.StatusForbidden)
+			return
+		}
+	}
+
+	if _, err := o.dispatcher.Dispatch(r.Context(), RemoveOrganizationMemberCommand{OrgID: orgID, Email: email}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListForUser обрабатывает GET /users/{email}/organizations — доступно самому
+// пользователю или вызывающим со scope "admin".
+func (o *OrganizationContr) ListForUser(w http.ResponseWriter, r *http.Request, email string) {
+	authUser, ok := UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "не найден аутентифицированный пользователь", http.StatusUnauthorized)
+		return
+	}
+	if authUser.Email != email && !hasScope(authUser.Scopes, "admin") {
+		http.Error(w, "недостаточно прав", http.StatusForbidden)
+		return
+	}
+
+	result, err := o.dispatcher.Dispatch(r.Context(), ListOrganizationsForUserQuery{Email: email})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func isOrganizationMember(members []Membership, email string) bool {
+	for _, m := range members {
+		if m.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// organizationRole возвращает роль вызывающего в members и признак того, что
+// он вообще состоит в организации.
+func organizationRole(members []Membership, email string) (string, bool) {
+	for _, m := range members {
+		if m.Email == email {
+			return m.Role, true
+		}
+	}
+	return "", false
+}
+
+// canGrantOrganizationRole сообщает, может ли участник с ролью callerRole
+// выдать или отозвать роль role у кого-то ещё: затронуть "owner" или "admin"
+// может только "owner" — это не даёт обычному участнику назначить себя или
+// сообщника владельцем организации (или разжаловать владельца).
+func canGrantOrganizationRole(callerRole, role string) bool {
+	if role == "owner" || role == "admin" {
+		return callerRole == "owner"
+	}
+	return true
+}
+
+// parseOrganizationMembersPath разбирает путь вида /organizations/{id}/members.
+func parseOrganizationMembersPath(path string) (int64, bool) {
+	const prefix = "/organizations/"
+	const suffix = "/members"
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
+		return 0, false
+	}
+	idStr := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// parseOrganizationMemberPath разбирает путь вида /organizations/{id}/members/{email}.
+func parseOrganizationMemberPath(path string) (int64, string, bool) {
+	const prefix = "/organizations/"
+	const infix = "/members/"
+	if !strings.HasPrefix(path, prefix) {
+		return 0, "", false
+	}
+	idStr, email, found := strings.Cut(strings.TrimPrefix(path, prefix), infix)
+	if !found || email == "" {
+		return 0, "", false
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return id, email, true
+}
+
+// parseUserOrganizationsPath разбирает путь вида /users/{email}/organizations.
+func parseUserOrganizationsPath(path string) (string, bool) {
+	const prefix = "/users/"
+	const suffix = "/organizations"
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
+		return "", false
+	}
+	email := strings.TrimSuffix(strings.TrimPrefix(path, prefix), suffix)
+	if email == "" {
+		return "", false
+	}
+	return email, true
+}
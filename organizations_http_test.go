@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeOrgRepo — минимальная in-memory реализация OrganizationRepository для
+// тестов контроллера, без обращения к реальной БД.
+type fakeOrgRepo struct {
+	members map[int64][]Membership
+}
+
+func newFakeOrgRepo(members ...Membership) *fakeOrgRepo {
+	repo := &fakeOrgRepo{members: make(map[int64][]Membership)}
+	for _, m := range members {
+		repo.members[m.OrgID] = append(repo.members[m.OrgID], m)
+	}
+	return repo
+}
+
+func (r *fakeOrgRepo) Create(ctx context.Context, org Organization) (Organization, error) {
+	return org, nil
+}
+
+func (r *fakeOrgRepo) AddMember(ctx context.Context, orgID int64, email, role string) error {
+	r.members[orgID] = append(r.members[orgID], Membership{Email: email, OrgID: orgID, Role: role})
+	return nil
+}
+
+func (r *fakeOrgRepo) RemoveMember(ctx context.Context, orgID int64, email string) error {
+	kept := r.members[orgID][:0]
+	for _, m := range r.members[orgID] {
+		if m.Email != email {
+			kept = append(kept, m)
+		}
+	}
+	r.members[orgID] = kept
+	return nil
+}
+
+func (r *fakeOrgRepo) ListForUser(ctx context.Context, email string) ([]Organization, error) {
+	return nil, nil
+}
+
+func (r *fakeOrgRepo) ListMembers(ctx context.Context, orgID int64) ([]Membership, error) {
+	return r.members[orgID], nil
+}
+
+func newOrgContrForTest(repo OrganizationRepository) *OrganizationContr {
+	dispatcher := NewDispatcher()
+	cmdHandlers := NewOrganizationCommandHandlers(repo)
+	queryHandlers := NewOrganizationQueryHandlers(NewCachedOrganizationRep(repo, NewOrganizationCache()))
+	dispatcher.RegisterCommand(AddOrganizationMemberCommand{}, cmdHandlers.HandleAddOrganizationMemberCommand)
+	dispatcher.RegisterCommand(RemoveOrganizationMemberCommand{}, cmdHandlers.HandleRemoveOrganizationMemberCommand)
+	dispatcher.RegisterQuery(ListOrganizationMembersQuery{}, queryHandlers.HandleListOrganizationMembersQuery)
+	return &OrganizationContr{dispatcher: dispatcher}
+}
+
+func TestAddMemberRejectsPlainMemberGrantingOwner(t *testing.T) {
+	repo := newFakeOrgRepo(Membership{Email: "plain@example.com", OrgID: 1, Role: "member"})
+	contr := newOrgContrForTest(repo)
+
+	body := bytes.NewBufferString(`{"email":"accomplice@example.com","role":"owner"}`)
+	req := httptest.NewRequest(http.MethodPost, "/organizations/1/members", body)
+	ctx := context.WithValue(req.Context(), userContextKey, AuthenticatedUser{Email: "plain@example.com"})
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	contr.AddMember(rec, req, 1)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("код ответа = %d, хотим %d (member не может выдать роль owner)", rec.Code, http.StatusForbidden)
+	}
+	if role, _ := organizationRole(repo.members[1], "accomplice@example.com"); role != "" {
+		t.Fatalf("роль owner не должна была достаться accomplice@example.com, но достались: %q", role)
+	}
+}
+
+func TestAddMemberAllowsOwnerGrantingOwner(t *testing.T) {
+	repo := newFakeOrgRepo(Membership{Email: "boss@example.com", OrgID: 1, Role: "owner"})
+	contr := newOrgContrForTest(repo)
+
+	body := bytes.NewBufferString(`{"email":"new-owner@example.com","role":"owner"}`)
+	req := httptest.NewRequest(http.MethodPost, "/organizations/1/members", body)
+	ctx := context.WithValue(req.Context(), userContextKey, AuthenticatedUser{Email: "boss@example.com"})
+	req = req.WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	contr.AddMember(rec, req, 1)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("код ответа = %d, хотим %d (owner может выдать роль owner)", rec.Code, http.StatusCreated)
+	}
+	if role, isMember := organizationRole(repo.members[1], "new-owner@example.com"); !isMember || role != "owner" {
+		t.Fatalf("new-owner@example.com должен был получить роль owner, получил (%q, %v)", role, isMember)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestCanGrantOrganizationRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		callerRole string
+		role       string
+		want       bool
+	}{
+		{"owner can grant owner", "owner", "owner", true},
+		{"owner can grant admin", "owner", "admin", true},
+		{"owner can grant member", "owner", "member", true},
+		{"admin cannot grant owner", "admin", "owner", false},
+		{"admin cannot grant admin", "admin", "admin", false},
+		{"admin can grant member", "admin", "member", true},
+		{"member cannot grant owner", "member", "owner", false},
+		{"member cannot grant admin", "member", "admin", false},
+		{"member can grant member", "member", "member", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canGrantOrganizationRole(tt.callerRole, tt.role); got != tt.want {
+				t.Errorf("canGrantOrganizationRole(%q, %q) = %v, хотим %v", tt.callerRole, tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrganizationRole(t *testing.T) {
+	members := []Membership{
+		{Email: "owner@example.com", OrgID: 1, Role: "owner"},
+		{Email: "member@example.com", OrgID: 1, Role: "member"},
+	}
+
+	if role, isMember := organizationRole(members, "owner@example.com"); role != "owner" || !isMember {
+		t.Errorf("organizationRole(owner) = (%q, %v), хотим (owner, true)", role, isMember)
+	}
+
+	if role, isMember := organizationRole(members, "stranger@example.com"); role != "" || isMember {
+		t.Errorf("organizationRole(stranger) = (%q, %v), хотим (\"\", false)", role, isMember)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisUserListKey = "users:list"
+const redisListLoadedKey = "users:list:loaded"
+
+// RedisCache — общий кэш пользователей поверх Redis, чтобы несколько реплик
+// сервиса видели одни и те же данные вместо изолированного UserCache на процесс.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisCache подключается к Redis по addr и хранит записи с временем жизни ttl.
+func NewRedisCache(addr string, ttl time.Duration) *RedisCache {
+	return &RedisCache{
+		client: redis.NewClient(&redis.Options{Addr: addr}),
+		ttl:    ttl,
+	}
+}
+
+func redisUserKey(email string) string {
+	return "user:" + email
+}
+
+func (c *RedisCache) Get(email string) (*User, bool) {
+	raw, err := c.client.Get(context.Background(), redisUserKey(email)).Bytes()
+	if err != nil {
+		return nil, false
+	}
+
+	var user User
+	if err := json.Unmarshal(raw, &user); err != nil {
+		return nil, false
+	}
+	return &user, true
+}
+
+func (c *RedisCache) Set(email string, user *User) {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	c.client.Set(ctx, redisUserKey(email), raw, c.ttl)
+	c.client.SAdd(ctx, redisUserListKey, email)
+}
+
+func (c *RedisCache) Delete(email string) {
+	ctx := context.Background()
+	c.client.Del(ctx, redisUserKey(email))
+	c.client.SRem(ctx, redisUserListKey, email)
+}
+
+func (c *RedisCache) List() []User {
+	ctx := context.Background()
+	emails, err := c.client.SMembers(ctx, redisUserListKey).Result()
+	if err != nil {
+		return nil
+	}
+
+	users := make([]User, 0, len(emails))
+	for _, email := range emails {
+		if user, found := c.Get(email); found {
+			users = append(users, *user)
+		}
+	}
+	return users
+}
+
+// IsListLoaded сообщает, отмечал ли кто-то из реплик список пользователей
+// полностью загруженным в Redis — этот флаг общий для всех реплик, в отличие
+// от локального булева поля.
+func (c *RedisCache) IsListLoaded() bool {
+	exists, err := c.client.Exists(context.Background(), redisListLoadedKey).Result()
+	return err == nil && exists > 0
+}
+
+// MarkListLoaded отмечает список пользователей полностью загруженным для всех
+// реплик, разделяющих этот Redis, на время ttl.
+func (c *RedisCache) MarkListLoaded() {
+	c.client.Set(context.Background(), redisListLoadedKey, "1", c.ttl)
+}
+
+// InvalidateListLoaded сбрасывает отметку о полной загрузке списка для всех
+// реплик — вызывается после создания нового пользователя.
+func (c *RedisCache) InvalidateListLoaded() {
+	c.client.Del(context.Background(), redisListLoadedKey)
+}